@@ -0,0 +1,62 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package config describes how a single log source should be collected and
+// decoded: its transport, framing, and any transport-specific settings.
+package config
+
+import "crypto/tls"
+
+// Source types understood by pkg/logs/input.
+const (
+	TCPType = "tcp"
+	UDPType = "udp"
+)
+
+// LogsConfig is the user-facing configuration for a single log source.
+type LogsConfig struct {
+	Type string
+	Port int
+
+	// TLSCert, TLSKey, TLSCA, ClientAuth and MinVersion configure a TLS
+	// listener for this source; TLSCert is required to serve it over TLS.
+	TLSCert    string
+	TLSKey     string
+	TLSCA      string
+	ClientAuth tls.ClientAuthType
+	MinVersion uint16
+}
+
+// LogSource represents a single configured source of logs, plus whatever
+// runtime settings the input pipeline needs beyond the user-facing config.
+type LogSource struct {
+	Name   string
+	Config *LogsConfig
+
+	// Framing selects the listener.Framer used to split this source's
+	// stream into messages. Empty means newline-delimited.
+	Framing string
+
+	// BufferSize and OverflowPolicy bound the tailer's internal buffer
+	// between the decoder and its output channel. Empty/zero means the
+	// tailer's defaults.
+	BufferSize     int
+	OverflowPolicy string
+
+	// EnableDataStreams opts this source into Data Streams Monitoring
+	// pathway propagation (pkg/logs/datastreams). Off by default: a
+	// tailer only looks for a pathway header on sources that are known to
+	// send one, rather than sniffing arbitrary untrusted payloads for a
+	// matching prefix.
+	EnableDataStreams bool
+}
+
+// NewLogSource returns a new LogSource wrapping cfg.
+func NewLogSource(name string, cfg *LogsConfig) *LogSource {
+	return &LogSource{
+		Name:   name,
+		Config: cfg,
+	}
+}
@@ -0,0 +1,98 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package datastreams propagates Data Streams Monitoring pathway context
+// through ingested log frames, so a pathway that started upstream of the
+// agent can be continued on read and resumed on egress.
+package datastreams
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/fnv"
+)
+
+// pathwayPrefix marks an encoded Pathway prepended to a frame's payload.
+// It's the wire-level counterpart of the `dd-pathway-ctx` header client
+// libraries attach to HTTP/gRPC requests.
+const pathwayPrefix = "dd-pathway-ctx:"
+
+// pathwayHeaderSize is the fixed size, in bytes, of an encoded Pathway.
+const pathwayHeaderSize = 24
+
+// Pathway identifies a Data Streams Monitoring pathway: the chain of
+// checkpoints a piece of data has passed through on its way to and through
+// the agent.
+type Pathway struct {
+	// Hash identifies the pathway: the node at which it started combined
+	// with every checkpoint hash since.
+	Hash uint64
+	// PathwayStart is the unix nanosecond timestamp the pathway started at.
+	PathwayStart int64
+	// EdgeStart is the unix nanosecond timestamp of the most recent
+	// checkpoint.
+	EdgeStart int64
+}
+
+// Decode extracts a Pathway from the start of frame, returning the
+// remaining payload with the pathway header stripped. ok is false if frame
+// carries no pathway header, in which case payload is frame unchanged.
+func Decode(frame []byte) (pathway Pathway, payload []byte, ok bool) {
+	if !bytes.HasPrefix(frame, []byte(pathwayPrefix)) {
+		return Pathway{}, frame, false
+	}
+	rest := frame[len(pathwayPrefix):]
+	if len(rest) < pathwayHeaderSize {
+		return Pathway{}, frame, false
+	}
+	header, body := rest[:pathwayHeaderSize], rest[pathwayHeaderSize:]
+
+	pathway = Pathway{
+		Hash:         binary.BigEndian.Uint64(header[0:8]),
+		PathwayStart: int64(binary.BigEndian.Uint64(header[8:16])),
+		EdgeStart:    int64(binary.BigEndian.Uint64(header[16:24])),
+	}
+	return pathway, body, true
+}
+
+// Encode prepends pathway's wire encoding to payload. Log producers using
+// the agent's client libraries call this to inject the pathway on the wire
+// before sending a frame. The header is fixed-size binary, so Decode needs
+// no delimiter to find the end of it.
+func Encode(pathway Pathway, payload []byte) []byte {
+	out := make([]byte, 0, len(pathwayPrefix)+pathwayHeaderSize+len(payload))
+	out = append(out, pathwayPrefix...)
+
+	var header [pathwayHeaderSize]byte
+	binary.BigEndian.PutUint64(header[0:8], pathway.Hash)
+	binary.BigEndian.PutUint64(header[8:16], uint64(pathway.PathwayStart))
+	binary.BigEndian.PutUint64(header[16:24], uint64(pathway.EdgeStart))
+	out = append(out, header[:]...)
+
+	return append(out, payload...)
+}
+
+// Checkpoint records that pathway passed through this point in the
+// pipeline - tagged `direction:<direction>,type:<transportType>,source:<source>`
+// - and returns the resulting pathway. Tailers call it with direction "in"
+// when continuing a pathway off the wire; producers call it with
+// direction "out" before Encode-ing the pathway back onto the wire.
+func Checkpoint(pathway Pathway, now int64, direction, transportType, source string) Pathway {
+	h := fnv.New64a()
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], pathway.Hash)
+	h.Write(buf[:])
+	h.Write([]byte("direction:" + direction + ",type:" + transportType + ",source:" + source))
+
+	start := pathway.PathwayStart
+	if pathway.Hash == 0 {
+		start = now
+	}
+	return Pathway{
+		Hash:         h.Sum64(),
+		PathwayStart: start,
+		EdgeStart:    now,
+	}
+}
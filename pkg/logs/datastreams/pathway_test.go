@@ -0,0 +1,69 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package datastreams
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEncodeDecodeRoundTrip(t *testing.T) {
+	pathway := Pathway{Hash: 42, PathwayStart: 100, EdgeStart: 200}
+	payload := []byte("the rest of the log line")
+
+	frame := Encode(pathway, payload)
+
+	got, rest, ok := Decode(frame)
+	if !ok {
+		t.Fatalf("Decode: expected ok")
+	}
+	if got != pathway {
+		t.Fatalf("got pathway %+v, want %+v", got, pathway)
+	}
+	if !bytes.Equal(rest, payload) {
+		t.Fatalf("got payload %q, want %q", rest, payload)
+	}
+}
+
+func TestDecodeNoPathway(t *testing.T) {
+	payload := []byte("a perfectly ordinary log line")
+
+	pathway, rest, ok := Decode(payload)
+	if ok {
+		t.Fatalf("Decode: expected ok=false for a frame with no pathway header")
+	}
+	if pathway != (Pathway{}) {
+		t.Fatalf("got non-zero pathway %+v for an unframed payload", pathway)
+	}
+	if !bytes.Equal(rest, payload) {
+		t.Fatalf("got payload %q, want original payload %q unchanged", rest, payload)
+	}
+}
+
+func TestDecodeTruncatedHeader(t *testing.T) {
+	frame := append([]byte(pathwayPrefix), 0, 1, 2)
+	if _, _, ok := Decode(frame); ok {
+		t.Fatalf("Decode: expected ok=false for a truncated pathway header")
+	}
+}
+
+func TestCheckpointStartsPathwayOnFirstHop(t *testing.T) {
+	first := Checkpoint(Pathway{}, 1000, "in", "tcp", "my-source")
+	if first.PathwayStart != 1000 {
+		t.Fatalf("got PathwayStart %d, want 1000 on the first hop", first.PathwayStart)
+	}
+	if first.EdgeStart != 1000 {
+		t.Fatalf("got EdgeStart %d, want 1000", first.EdgeStart)
+	}
+
+	second := Checkpoint(first, 2000, "out", "tcp", "my-source")
+	if second.PathwayStart != first.PathwayStart {
+		t.Fatalf("PathwayStart changed across hops: got %d, want %d", second.PathwayStart, first.PathwayStart)
+	}
+	if second.Hash == first.Hash {
+		t.Fatalf("expected a new hop to change the pathway hash")
+	}
+}
@@ -0,0 +1,80 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package decoder turns raw frames read off a connection into decoded
+// messages ready to be forwarded.
+package decoder
+
+import "github.com/DataDog/datadog-agent/pkg/logs/config"
+
+// Input is a single raw frame read off a connection, queued for decoding.
+type Input struct {
+	Content []byte
+	release func()
+}
+
+// NewInput returns an Input wrapping content. release, if non-nil, is
+// called by Reset once the decoder is done reading Content, so a
+// pool-backed buffer can be recycled.
+func NewInput(content []byte, release func()) *Input {
+	return &Input{Content: content, release: release}
+}
+
+// Reset releases any pool buffer backing Content and clears the Input.
+func (i *Input) Reset() {
+	if i.release != nil {
+		i.release()
+		i.release = nil
+	}
+	i.Content = nil
+}
+
+// Output is a fully decoded message, ready to be wrapped in a
+// message.Message.
+type Output struct {
+	Content []byte
+}
+
+// Decoder turns each raw Input into one decoded Output, copying its content
+// out of the Input's buffer. It does not split or aggregate Inputs.
+type Decoder struct {
+	InputChan  chan *Input
+	OutputChan chan Output
+}
+
+// InitializeDecoder returns a new Decoder for source.
+func InitializeDecoder(source *config.LogSource) *Decoder {
+	return &Decoder{
+		InputChan:  make(chan *Input),
+		OutputChan: make(chan Output),
+	}
+}
+
+// Start begins processing Inputs off InputChan.
+func (d *Decoder) Start() {
+	go d.run()
+}
+
+// Stop stops the decoder by closing InputChan. The caller must be done
+// sending on InputChan before calling Stop, and must not send afterwards.
+// Any Input already queued is still decoded and delivered on OutputChan,
+// which is only closed once that drain completes, so a slow consumer can
+// read everything the decoder had already picked up off the wire.
+func (d *Decoder) Stop() {
+	close(d.InputChan)
+}
+
+func (d *Decoder) run() {
+	defer close(d.OutputChan)
+	for input := range d.InputChan {
+		// Copy out of input.Content into a buffer this decoder owns
+		// before recycling whatever pool buffer backed it.
+		content := make([]byte, len(input.Content))
+		copy(content, input.Content)
+		input.Reset()
+
+		d.OutputChan <- Output{Content: content}
+	}
+}
@@ -0,0 +1,47 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package decoder
+
+import (
+	"testing"
+	"time"
+)
+
+// TestDecoderStopDrainsPendingOutput is a regression test for a race where
+// Stop could drop a message that had already been read off the wire and
+// decoded, but not yet delivered to OutputChan. Every Input queued on
+// InputChan before Stop is called must still flush through to OutputChan
+// before the decoder exits and closes it.
+func TestDecoderStopDrainsPendingOutput(t *testing.T) {
+	d := InitializeDecoder(nil)
+	d.Start()
+
+	const n = 200
+	go func() {
+		for i := 0; i < n; i++ {
+			d.InputChan <- NewInput([]byte("line"), nil)
+		}
+		d.Stop()
+	}()
+
+	done := make(chan int, 1)
+	go func() {
+		received := 0
+		for range d.OutputChan {
+			received++
+		}
+		done <- received
+	}()
+
+	select {
+	case received := <-done:
+		if received != n {
+			t.Fatalf("got %d messages, want %d", received, n)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for decoder to drain and close OutputChan")
+	}
+}
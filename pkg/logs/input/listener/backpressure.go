@@ -0,0 +1,116 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// OverflowPolicy controls what happens when a tailer's bounded buffer is
+// full and another message needs to be forwarded. It mirrors the
+// `OverflowPolicy` field on config.LogSource.
+type OverflowPolicy string
+
+const (
+	// BlockOverflowPolicy blocks the decoder until the consumer frees
+	// space in the buffer. This is the historical behavior.
+	BlockOverflowPolicy OverflowPolicy = "block"
+	// DropOldestOverflowPolicy evicts the oldest buffered message to make
+	// room for the incoming one.
+	DropOldestOverflowPolicy OverflowPolicy = "drop_oldest"
+	// DropNewestOverflowPolicy discards the incoming message, keeping
+	// whatever is already buffered.
+	DropNewestOverflowPolicy OverflowPolicy = "drop_newest"
+)
+
+// defaultBufferSize is used when a LogSource doesn't configure one.
+const defaultBufferSize = 100
+
+// enqueue applies the tailer's overflow policy to push msg onto the bounded
+// buffer sitting between the decoder and outputChan. It never blocks on
+// outputChan directly. Under the drop policies a slow consumer can't stall
+// reads off the socket; under BlockOverflowPolicy a full buffer makes
+// enqueue wait, which backs up the decoder and then the read loop.
+func (t *Tailer) enqueue(msg message.Message) {
+	switch t.overflowPolicy {
+	case DropNewestOverflowPolicy:
+		select {
+		case t.buffer <- msg:
+		case <-t.consumerGone:
+		default:
+			tlmDropped.Inc(t.source.Name, "drop_newest")
+		}
+	case DropOldestOverflowPolicy:
+		for {
+			select {
+			case t.buffer <- msg:
+				return
+			case <-t.consumerGone:
+				return
+			default:
+			}
+			select {
+			case <-t.buffer:
+				tlmDropped.Inc(t.source.Name, "drop_oldest")
+			default:
+			}
+		}
+	default: // BlockOverflowPolicy
+		select {
+		case t.buffer <- msg:
+		case <-t.consumerGone:
+		}
+	}
+	tlmBuffered.Set(float64(len(t.buffer)), t.source.Name)
+}
+
+// drainBuffer forwards buffered messages to outputChan until the buffer is
+// closed or the consumer disappears. It runs in its own goroutine so a slow
+// or gone consumer blocks this loop directly; the socket read loop only
+// backs up behind it under BlockOverflowPolicy, once the buffer is full.
+func (t *Tailer) drainBuffer() {
+	defer close(t.bufferDrained)
+	for {
+		select {
+		case msg, ok := <-t.buffer:
+			if !ok {
+				return
+			}
+			select {
+			case t.outputChan <- msg:
+				tlmBuffered.Set(float64(len(t.buffer)), t.source.Name)
+			case <-t.consumerGone:
+				return
+			}
+		case <-t.consumerGone:
+			return
+		}
+	}
+}
+
+// WatchConsumerGone arranges for the tailer to stop forwarding buffered
+// messages and release its goroutines once gone is closed, instead of
+// deadlocking on a send to outputChan that will never be read again.
+func (t *Tailer) WatchConsumerGone(gone <-chan struct{}) {
+	go func() {
+		select {
+		case <-gone:
+			close(t.consumerGone)
+		case <-t.closed:
+		}
+	}()
+}
+
+// Closed returns true once the tailer has fully stopped, so the surrounding
+// Listener can prune it and free its goroutines.
+func (t *Tailer) Closed() bool {
+	select {
+	case <-t.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,191 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"context"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// newOverflowTestTailer returns a Tailer with its own bounded buffer
+// configured, but never Started, so tests can exercise enqueue directly
+// without a live decoder or socket reads racing with them.
+func newOverflowTestTailer(t *testing.T, policy OverflowPolicy, bufferSize int) *Tailer {
+	t.Helper()
+	source := config.NewLogSource("test", &config.LogsConfig{})
+	source.OverflowPolicy = string(policy)
+	source.BufferSize = bufferSize
+	server, client := net.Pipe()
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+	return NewTailer(source, server, nil, func(*Tailer) ([]byte, error) { return nil, io.EOF })
+}
+
+func drainBufferContents(tailer *Tailer) []string {
+	var got []string
+	for {
+		select {
+		case msg := <-tailer.buffer:
+			got = append(got, string(msg.Content))
+		default:
+			return got
+		}
+	}
+}
+
+func TestEnqueueDropNewest(t *testing.T) {
+	tailer := newOverflowTestTailer(t, DropNewestOverflowPolicy, 2)
+	tailer.enqueue(message.New([]byte("a"), nil, ""))
+	tailer.enqueue(message.New([]byte("b"), nil, ""))
+	tailer.enqueue(message.New([]byte("c"), nil, "")) // buffer full: dropped
+
+	got := drainBufferContents(tailer)
+	want := []string{"a", "b"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestEnqueueDropOldest(t *testing.T) {
+	tailer := newOverflowTestTailer(t, DropOldestOverflowPolicy, 2)
+	tailer.enqueue(message.New([]byte("a"), nil, ""))
+	tailer.enqueue(message.New([]byte("b"), nil, ""))
+	tailer.enqueue(message.New([]byte("c"), nil, "")) // evicts "a"
+
+	got := drainBufferContents(tailer)
+	want := []string{"b", "c"}
+	if !equalStrings(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestEnqueueBlocksUntilConsumed(t *testing.T) {
+	tailer := newOverflowTestTailer(t, BlockOverflowPolicy, 1)
+	tailer.enqueue(message.New([]byte("a"), nil, ""))
+
+	done := make(chan struct{})
+	go func() {
+		tailer.enqueue(message.New([]byte("b"), nil, ""))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("enqueue returned before the buffer had room")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	<-tailer.buffer // drain "a", freeing a slot
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("enqueue stayed blocked after the buffer was drained")
+	}
+}
+
+func TestWatchConsumerGoneUnblocksEnqueue(t *testing.T) {
+	tailer := newOverflowTestTailer(t, BlockOverflowPolicy, 1)
+	gone := make(chan struct{})
+	tailer.WatchConsumerGone(gone)
+	tailer.enqueue(message.New([]byte("a"), nil, "")) // fills the only slot
+
+	done := make(chan struct{})
+	go func() {
+		tailer.enqueue(message.New([]byte("b"), nil, ""))
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("enqueue returned before the consumer went away")
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	close(gone)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("enqueue stayed blocked after the consumer went away")
+	}
+}
+
+func TestListenerPruneClosedTailers(t *testing.T) {
+	open := &Tailer{closed: make(chan struct{})}
+	closedTailer := &Tailer{closed: make(chan struct{})}
+	close(closedTailer.closed)
+
+	l := &Listener{tailers: []*Tailer{open, closedTailer}}
+	l.pruneClosedTailers()
+
+	if len(l.tailers) != 1 || l.tailers[0] != open {
+		t.Fatalf("got %v, want only the open tailer", l.tailers)
+	}
+}
+
+// TestListenerWiresConsumerGoneIntoTailers is a regression test: Listener
+// must pass its consumerGone channel on to every Tailer it spawns, so that
+// an outputChan consumer going away unblocks a tailer stuck trying to
+// deliver to it instead of leaking its goroutines until Shutdown.
+func TestListenerWiresConsumerGoneIntoTailers(t *testing.T) {
+	source := config.NewLogSource("test", &config.LogsConfig{})
+	source.OverflowPolicy = string(BlockOverflowPolicy)
+	source.BufferSize = 1
+
+	outputChan := make(chan message.Message) // never drained
+	gone := make(chan struct{})
+	l := NewListener(source, outputChan, nil, gone)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("net.Listen: %v", err)
+	}
+	l.Serve(ln)
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatalf("net.Dial: %v", err)
+	}
+	defer conn.Close()
+
+	// write more newline-delimited messages than the buffer can hold, so
+	// the tailer's forwarder ends up stuck trying to deliver to outputChan.
+	for i := 0; i < 5; i++ {
+		if _, err := conn.Write([]byte("line\n")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	close(gone)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := l.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown did not complete after the consumer went away: %v", err)
+	}
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// maxLengthPrefixedFrameLength caps the declared payload size so a
+// malformed frame can't make us allocate an unbounded buffer.
+const maxLengthPrefixedFrameLength = 16 * 1024 * 1024
+
+// lengthPrefixedFramer implements length-prefixed binary framing as used by
+// Cap'n Proto and msgpack streams: a 4-byte big-endian length followed by
+// exactly that many bytes of payload.
+type lengthPrefixedFramer struct{}
+
+// Frame implements Framer.
+func (f *lengthPrefixedFramer) Frame(r *bufio.Reader) ([]byte, func(), error) {
+	var length uint32
+	if err := binary.Read(r, binary.BigEndian, &length); err != nil {
+		return nil, nil, err
+	}
+	if length == 0 || length > maxLengthPrefixedFrameLength {
+		return nil, nil, fmt.Errorf("length-prefixed frame size %d out of bounds", length)
+	}
+
+	// frame is pool-backed: the caller passes release through to
+	// decoder.NewInput, and the decoder returns the buffer once it has
+	// copied out of frame (decoder.Input.Reset).
+	frame := getBuffer(int(length))
+	if _, err := io.ReadFull(r, frame); err != nil {
+		putBuffer(frame)
+		return nil, nil, err
+	}
+	return frame, func() { putBuffer(frame) }, nil
+}
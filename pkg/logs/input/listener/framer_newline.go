@@ -0,0 +1,24 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+)
+
+// newlineFramer splits a stream on '\n', the framing the tailer has always
+// used. It is the default when a LogSource sets no Framing.
+type newlineFramer struct{}
+
+// Frame implements Framer. The returned slice is freshly allocated by
+// bufio.Reader.ReadBytes, not pool-backed, so there's nothing to release.
+func (f *newlineFramer) Frame(r *bufio.Reader) ([]byte, func(), error) {
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, nil, err
+	}
+	return line, nil, nil
+}
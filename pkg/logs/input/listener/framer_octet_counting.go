@@ -0,0 +1,74 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// maxOctetCountingFrameLength caps the ASCII length prefix so a malformed or
+// malicious sender can't make us allocate an unbounded buffer.
+const maxOctetCountingFrameLength = 1024 * 1024
+
+// maxOctetCountingPrefixLength caps the number of bytes we'll read while
+// looking for the space that terminates the length prefix. It's sized to
+// comfortably fit the decimal digits of maxOctetCountingFrameLength with
+// room to spare; a sender that never emits a space byte is refused instead
+// of growing the reader's buffer without bound.
+const maxOctetCountingPrefixLength = 16
+
+// octetCountingFramer implements the RFC 6587 / RFC 5425 octet-counting
+// transport framing: an ASCII decimal length, a single space, then exactly
+// that many bytes of message.
+type octetCountingFramer struct{}
+
+// Frame implements Framer.
+func (f *octetCountingFramer) Frame(r *bufio.Reader) ([]byte, func(), error) {
+	lengthPrefix, err := readUntilSpace(r, maxOctetCountingPrefixLength)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var length int
+	if _, err := fmt.Sscanf(lengthPrefix, "%d", &length); err != nil {
+		return nil, nil, fmt.Errorf("invalid octet-counting length prefix %q: %w", lengthPrefix, err)
+	}
+	if length <= 0 || length > maxOctetCountingFrameLength {
+		return nil, nil, fmt.Errorf("octet-counting length %d out of bounds", length)
+	}
+
+	// frame is pool-backed: the caller passes release through to
+	// decoder.NewInput, and the decoder returns the buffer once it has
+	// copied out of frame (decoder.Input.Reset).
+	frame := getBuffer(length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		putBuffer(frame)
+		return nil, nil, err
+	}
+	return frame, func() { putBuffer(frame) }, nil
+}
+
+// readUntilSpace reads bytes off r up to the terminating space, refusing to
+// read more than maxLen bytes first so an untrusted sender that never emits
+// a space can't make r buffer unboundedly.
+func readUntilSpace(r *bufio.Reader, maxLen int) (string, error) {
+	var prefix []byte
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		if b == ' ' {
+			return string(prefix), nil
+		}
+		prefix = append(prefix, b)
+		if len(prefix) > maxLen {
+			return "", fmt.Errorf("octet-counting length prefix exceeds %d bytes", maxLen)
+		}
+	}
+}
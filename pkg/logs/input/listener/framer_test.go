@@ -0,0 +1,142 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestOctetCountingFramer(t *testing.T) {
+	f := &octetCountingFramer{}
+
+	t.Run("single frame", func(t *testing.T) {
+		r := bufio.NewReader(strings.NewReader("5 hello"))
+		frame, release, err := f.Frame(r)
+		if err != nil {
+			t.Fatalf("Frame: %v", err)
+		}
+		if string(frame) != "hello" {
+			t.Fatalf("got frame %q, want %q", frame, "hello")
+		}
+		if release != nil {
+			release()
+		}
+	})
+
+	t.Run("truncated frame", func(t *testing.T) {
+		r := bufio.NewReader(strings.NewReader("10 abc"))
+		if _, _, err := f.Frame(r); err == nil {
+			t.Fatalf("expected an error for a truncated frame")
+		}
+	})
+
+	t.Run("oversized length", func(t *testing.T) {
+		r := bufio.NewReader(strings.NewReader("99999999999 abc"))
+		if _, _, err := f.Frame(r); err == nil {
+			t.Fatalf("expected an error for a length exceeding maxOctetCountingFrameLength")
+		}
+	})
+
+	t.Run("malformed prefix", func(t *testing.T) {
+		r := bufio.NewReader(strings.NewReader("abc hello"))
+		if _, _, err := f.Frame(r); err == nil {
+			t.Fatalf("expected an error for a non-numeric length prefix")
+		}
+	})
+
+	t.Run("unbounded prefix is rejected instead of buffered forever", func(t *testing.T) {
+		// a sender that never emits a space byte must not make Frame read
+		// (and buffer) without limit.
+		r := bufio.NewReader(bytes.NewReader(bytes.Repeat([]byte("1"), 10*maxOctetCountingPrefixLength)))
+		if _, _, err := f.Frame(r); err == nil {
+			t.Fatalf("expected an error once the prefix exceeds maxOctetCountingPrefixLength")
+		}
+	})
+}
+
+func TestLengthPrefixedFramer(t *testing.T) {
+	f := &lengthPrefixedFramer{}
+
+	t.Run("single frame", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.Write([]byte{0, 0, 0, 5})
+		buf.WriteString("hello")
+		r := bufio.NewReader(&buf)
+
+		frame, release, err := f.Frame(r)
+		if err != nil {
+			t.Fatalf("Frame: %v", err)
+		}
+		if string(frame) != "hello" {
+			t.Fatalf("got frame %q, want %q", frame, "hello")
+		}
+		if release != nil {
+			release()
+		}
+	})
+
+	t.Run("truncated frame", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.Write([]byte{0, 0, 0, 10})
+		buf.WriteString("abc")
+		r := bufio.NewReader(&buf)
+
+		if _, _, err := f.Frame(r); err == nil {
+			t.Fatalf("expected an error for a truncated frame")
+		}
+	})
+
+	t.Run("oversized length", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.Write([]byte{0xFF, 0xFF, 0xFF, 0xFF})
+		r := bufio.NewReader(&buf)
+
+		if _, _, err := f.Frame(r); err == nil {
+			t.Fatalf("expected an error for a length exceeding maxLengthPrefixedFrameLength")
+		}
+	})
+
+	t.Run("zero length", func(t *testing.T) {
+		var buf bytes.Buffer
+		buf.Write([]byte{0, 0, 0, 0})
+		r := bufio.NewReader(&buf)
+
+		if _, _, err := f.Frame(r); err == nil {
+			t.Fatalf("expected an error for a zero-length frame")
+		}
+	})
+}
+
+func TestNewFramer(t *testing.T) {
+	cases := []struct {
+		framing Framing
+		want    Framer
+	}{
+		{NewlineFraming, &newlineFramer{}},
+		{OctetCountingFraming, &octetCountingFramer{}},
+		{LengthPrefixedFraming, &lengthPrefixedFramer{}},
+	}
+	for _, c := range cases {
+		got, err := NewFramer(c.framing)
+		if err != nil {
+			t.Fatalf("NewFramer(%q): %v", c.framing, err)
+		}
+		if got == nil {
+			t.Fatalf("NewFramer(%q) returned nil", c.framing)
+		}
+		if reflect.TypeOf(got) != reflect.TypeOf(c.want) {
+			t.Fatalf("NewFramer(%q) returned %T, want %T", c.framing, got, c.want)
+		}
+	}
+
+	if _, err := NewFramer("bogus"); err == nil {
+		t.Fatalf("expected an error for an unknown framing")
+	}
+}
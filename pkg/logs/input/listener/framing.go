@@ -0,0 +1,54 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"bufio"
+	"fmt"
+)
+
+// Framing identifies how a Tailer should split a byte stream into discrete
+// messages. It mirrors the `Framing` field on config.LogSource.
+type Framing string
+
+const (
+	// NewlineFraming splits on '\n', the historical behavior of the tailer.
+	NewlineFraming Framing = "newline"
+	// OctetCountingFraming implements the RFC 6587 / RFC 5425 "<len> <msg>"
+	// transport framing used by syslog senders that don't rely on newlines.
+	OctetCountingFraming Framing = "octet_counting"
+	// LengthPrefixedFraming reads a uint32 big-endian length followed by
+	// that many bytes of payload, as used by Cap'n Proto and msgpack streams.
+	LengthPrefixedFraming Framing = "length_prefixed"
+)
+
+// Framer extracts a single message frame from a connection. Implementations
+// own whatever buffering is required to delimit a frame, so a frame that
+// spans several TCP reads - or several frames that arrive in a single read -
+// are handled transparently by the caller reusing the same bufio.Reader
+// across calls.
+type Framer interface {
+	// Frame returns the next frame's payload, blocking until a full frame
+	// is available or the underlying reader returns an error. release, if
+	// non-nil, returns frame's backing buffer to its pool and must be
+	// called once the caller is done with frame.
+	Frame(r *bufio.Reader) (frame []byte, release func(), err error)
+}
+
+// NewFramer returns the Framer implementation for the given Framing, or an
+// error if the framing is not recognized.
+func NewFramer(framing Framing) (Framer, error) {
+	switch framing {
+	case "", NewlineFraming:
+		return &newlineFramer{}, nil
+	case OctetCountingFraming:
+		return &octetCountingFramer{}, nil
+	case LengthPrefixedFraming:
+		return &lengthPrefixedFramer{}, nil
+	default:
+		return nil, fmt.Errorf("unknown framing: %q", framing)
+	}
+}
@@ -0,0 +1,18 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import "github.com/DataDog/datadog-agent/pkg/telemetry"
+
+// tlmDropped and tlmBuffered back the logs.tcp.dropped and logs.tcp.buffered
+// metrics: how many frames a tailer's bounded buffer has had to discard, and
+// how many it currently holds.
+var (
+	tlmDropped = telemetry.NewCounter("logs_tcp", "dropped",
+		[]string{"source", "reason"}, "Frames dropped by a TCP/UDP tailer's bounded buffer")
+	tlmBuffered = telemetry.NewGauge("logs_tcp", "buffered",
+		[]string{"source"}, "Frames currently held in a TCP/UDP tailer's bounded buffer")
+)
@@ -0,0 +1,52 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import "sync"
+
+// bufferSizeClasses are the read-buffer sizes kept in bufferPools, smallest
+// first. A request is rounded up to the first class that fits it.
+var bufferSizeClasses = []int{4 * 1024, 16 * 1024, 64 * 1024, 256 * 1024}
+
+var bufferPools = func() []*sync.Pool {
+	pools := make([]*sync.Pool, len(bufferSizeClasses))
+	for i, size := range bufferSizeClasses {
+		size := size
+		pools[i] = &sync.Pool{
+			New: func() interface{} {
+				buf := make([]byte, size)
+				return &buf
+			},
+		}
+	}
+	return pools
+}()
+
+// getBuffer returns a []byte of at least size bytes from the pool matching
+// its size class, avoiding a fresh allocation on the hot read path.
+func getBuffer(size int) []byte {
+	for i, class := range bufferSizeClasses {
+		if size <= class {
+			buf := *bufferPools[i].Get().(*[]byte)
+			return buf[:size]
+		}
+	}
+	// larger than any size class: not worth pooling.
+	return make([]byte, size)
+}
+
+// putBuffer returns a buffer obtained from getBuffer to its pool. Callers
+// must not use buf after calling putBuffer.
+func putBuffer(buf []byte) {
+	capacity := cap(buf)
+	for i, class := range bufferSizeClasses {
+		if capacity == class {
+			full := buf[:class]
+			bufferPools[i].Put(&full)
+			return
+		}
+	}
+}
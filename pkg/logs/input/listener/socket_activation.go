@@ -0,0 +1,42 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// listenFDsStart is the first inherited file descriptor number under the
+// systemd socket-activation convention; fds 0-2 are stdio.
+const listenFDsStart = 3
+
+// ListenerFromFileDescriptor returns the net.Listener at fdIndex inherited
+// from a parent process via systemd-style socket activation (the
+// LISTEN_FDS / LISTEN_PID environment variables), so the agent can restart
+// without closing its listening socket. Connections the parent had already
+// accepted are not inherited. It returns a nil listener and
+// a nil error if no socket was inherited at that index, so callers can fall
+// back to binding their own.
+func ListenerFromFileDescriptor(fdIndex int) (net.Listener, error) {
+	count, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fdIndex >= count {
+		return nil, nil
+	}
+	if pid, err := strconv.Atoi(os.Getenv("LISTEN_PID")); err != nil || pid != os.Getpid() {
+		return nil, nil
+	}
+
+	fd := uintptr(listenFDsStart + fdIndex)
+	f := os.NewFile(fd, fmt.Sprintf("listen-fd-%d", fdIndex))
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("inheriting listener from fd %d: %w", fd, err)
+	}
+	return ln, nil
+}
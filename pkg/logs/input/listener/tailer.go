@@ -6,61 +6,160 @@
 package listener
 
 import (
+	"bufio"
+	"context"
 	"net"
+	"time"
 
 	"github.com/DataDog/datadog-agent/pkg/util/log"
 
 	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/datastreams"
 	"github.com/DataDog/datadog-agent/pkg/logs/decoder"
 	"github.com/DataDog/datadog-agent/pkg/logs/message"
 )
 
 // Tailer reads data from a connection
 type Tailer struct {
-	source     *config.LogSource
-	conn       net.Conn
-	outputChan chan message.Message
-	read       func(*Tailer) ([]byte, error)
-	decoder    *decoder.Decoder
-	stop       chan struct{}
-	done       chan struct{}
+	source         *config.LogSource
+	conn           net.Conn
+	outputChan     chan message.Message
+	read           func(*Tailer) ([]byte, error)
+	reader         *bufio.Reader
+	framer         Framer
+	frameRelease   func()
+	decoder        *decoder.Decoder
+	overflowPolicy OverflowPolicy
+	buffer         chan message.Message
+	consumerGone   chan struct{}
+	bufferDrained  chan struct{}
+	stop           chan struct{}
+	done           chan struct{}
+	closed         chan struct{}
 }
 
-// NewTailer returns a new Tailer
+// NewTailer returns a new Tailer. read is used to pull a raw frame off conn;
+// implementations that only need newline-delimited framing can keep passing
+// their own read function. When read is nil, source.Framing picks one of
+// the framers in this package (newline-delimited when unset); a non-nil
+// read takes precedence and source.Framing is then ignored.
 func NewTailer(source *config.LogSource, conn net.Conn, outputChan chan message.Message, read func(*Tailer) ([]byte, error)) *Tailer {
-	return &Tailer{
-		source:     source,
-		conn:       conn,
-		outputChan: outputChan,
-		read:       read,
-		decoder:    decoder.InitializeDecoder(source),
-		stop:       make(chan struct{}, 1),
-		done:       make(chan struct{}, 1),
+	bufferSize := source.BufferSize
+	if bufferSize <= 0 {
+		bufferSize = defaultBufferSize
 	}
+	t := &Tailer{
+		source:         source,
+		conn:           conn,
+		outputChan:     outputChan,
+		reader:         bufio.NewReader(conn),
+		decoder:        decoder.InitializeDecoder(source),
+		overflowPolicy: OverflowPolicy(source.OverflowPolicy),
+		buffer:         make(chan message.Message, bufferSize),
+		consumerGone:   make(chan struct{}),
+		bufferDrained:  make(chan struct{}),
+		stop:           make(chan struct{}, 1),
+		done:           make(chan struct{}, 1),
+		closed:         make(chan struct{}),
+	}
+	if read != nil {
+		t.read = read
+	} else {
+		framer, err := NewFramer(Framing(source.Framing))
+		if err != nil {
+			log.Warnf("Falling back to newline framing: %v", err)
+			framer = &newlineFramer{}
+		}
+		t.framer = framer
+		t.read = readFramed
+	}
+	return t
+}
+
+// readFramed is the default `read` implementation: it delegates the framing
+// to t.framer, which owns the buffering needed to reassemble frames that
+// span several TCP reads. The framer's release func, if any, is stashed on
+// t so readForever can pass it to the decoder once the frame is queued.
+func readFramed(t *Tailer) ([]byte, error) {
+	frame, release, err := t.framer.Frame(t.reader)
+	t.frameRelease = release
+	return frame, err
 }
 
 // Start prepares the tailer to read and decode data from the connection
 func (t *Tailer) Start() {
+	go t.drainBuffer()
 	go t.forwardMessages()
 	t.decoder.Start()
 	go t.readForever()
 }
 
-// Stop stops the tailer and waits for the decoder to be flushed
-func (t *Tailer) Stop() {
+// Shutdown gracefully stops the tailer: it stops accepting new bytes off
+// the connection, waits up to ctx's deadline for the decoder and forwarder
+// to flush whatever they already have buffered, then hard-closes the
+// socket. It replaces the old fire-and-forget Stop, which closed the
+// connection before the decoder had a chance to drain a partially-buffered
+// frame.
+//
+// If ctx's deadline passes first, Shutdown returns ctx.Err() without
+// waiting any further: the drain can still be stuck if nothing is reading
+// outputChan (WatchConsumerGone is opt-in, not wired by default), and
+// there is no way to force it to finish on a timeline. The drain keeps
+// running in the background and Closed() flips once it eventually does.
+func (t *Tailer) Shutdown(ctx context.Context) error {
 	t.stop <- struct{}{}
-	t.conn.Close()
-	<-t.done
+	// unblock a read that's already in flight without tearing down the
+	// socket, so decoded output still queued up can keep draining.
+	t.conn.SetReadDeadline(time.Now())
+
+	select {
+	case <-t.done:
+		t.conn.Close()
+		close(t.closed)
+		return nil
+	case <-ctx.Done():
+		t.conn.Close()
+		go func() {
+			<-t.done
+			close(t.closed)
+		}()
+		return ctx.Err()
+	}
 }
 
-// forwardMessages forwards messages to output channel
+// Stop stops the tailer and waits indefinitely for the decoder to flush.
+//
+// Deprecated: use Shutdown with a bounded context instead.
+func (t *Tailer) Stop() {
+	t.Shutdown(context.Background())
+}
+
+// forwardMessages reads decoded output and hands it to drainBuffer through
+// the bounded buffer. Under the drop policies a slow outputChan consumer
+// never blocks the decoder or the socket read loop; under
+// BlockOverflowPolicy it does once the buffer is full.
 func (t *Tailer) forwardMessages() {
 	defer func() {
+		close(t.buffer)
+		<-t.bufferDrained
 		// the decoder has successfully been flushed
 		t.done <- struct{}{}
 	}()
 	for output := range t.decoder.OutputChan {
-		t.outputChan <- message.New(output.Content, message.NewOrigin(t.source), "")
+		content := output.Content
+		origin := message.NewOrigin(t.source)
+
+		// continue a Data Streams pathway carried on the wire, if any. Only
+		// sources that explicitly opt in are scanned, so we don't strip a
+		// matching-looking prefix off an arbitrary untrusted payload.
+		if t.source.EnableDataStreams {
+			if pathway, payload, ok := datastreams.Decode(content); ok {
+				origin.SetPathway(datastreams.Checkpoint(pathway, time.Now().UnixNano(), "in", "tcp", t.source.Name))
+				content = payload
+			}
+		}
+
+		t.enqueue(message.New(content, origin, ""))
 	}
 }
 
@@ -82,7 +181,9 @@ func (t *Tailer) readForever() {
 				log.Warnf("Couldn't read message from connection: %v", err)
 				return
 			}
-			t.decoder.InputChan <- decoder.NewInput(data)
+			release := t.frameRelease
+			t.frameRelease = nil
+			t.decoder.InputChan <- decoder.NewInput(data, release)
 		}
 	}
-}
\ No newline at end of file
+}
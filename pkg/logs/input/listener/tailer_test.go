@@ -0,0 +1,118 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// TestShutdownReturnsAtDeadlineWhenConsumerStuck is a regression test:
+// Shutdown must return ctx.Err() once ctx's deadline passes, even if
+// nothing is draining outputChan and no consumerGone channel was wired
+// (it's opt-in, not automatic). The drain keeps running in the
+// background; Shutdown itself must not block waiting on it.
+func TestShutdownReturnsAtDeadlineWhenConsumerStuck(t *testing.T) {
+	source := config.NewLogSource("test", &config.LogsConfig{})
+	source.OverflowPolicy = string(BlockOverflowPolicy)
+	source.BufferSize = 1
+
+	serverConn, clientConn := net.Pipe()
+	defer clientConn.Close()
+
+	outputChan := make(chan message.Message) // never drained, no consumerGone wired
+	tailer := NewTailer(source, serverConn, outputChan, nil)
+	tailer.Start()
+
+	// Write enough frames to back up the decoder -> buffer -> outputChan
+	// chain, so something downstream of the socket ends up stuck with
+	// nobody to unblock it. Once the chain is full, net.Pipe's synchronous
+	// Write blocks - this writer isn't expected to finish; clientConn.Close
+	// above unblocks it on test cleanup.
+	go func() {
+		for i := 0; i < 10; i++ {
+			if _, err := clientConn.Write([]byte("line\n")); err != nil {
+				return
+			}
+		}
+	}()
+	time.Sleep(100 * time.Millisecond) // let the chain actually back up
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() { done <- tailer.Shutdown(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("got err %v, want context.DeadlineExceeded", err)
+		}
+		if elapsed := time.Since(start); elapsed > 2*time.Second {
+			t.Fatalf("Shutdown took %v, expected to return promptly at the deadline", elapsed)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Shutdown did not return within 5s of its 200ms deadline")
+	}
+}
+
+// BenchmarkTailerThroughput drives a Tailer over a continuous
+// octet-counting stream, demonstrating the allocation reduction from the
+// pooled read buffers in pool.go. Octet-counting framing is what actually
+// exercises getBuffer/putBuffer - newline framing reads off bufio's own
+// buffer and never touches the pool.
+func BenchmarkTailerThroughput(b *testing.B) {
+	payload := make([]byte, 199)
+	frame := []byte(fmt.Sprintf("%d %s", len(payload), payload))
+
+	source := config.NewLogSource("bench", &config.LogsConfig{Type: config.TCPType})
+	source.Framing = string(OctetCountingFraming)
+	outputChan := make(chan message.Message, 1000)
+	serverConn, clientConn := net.Pipe()
+
+	tailer := NewTailer(source, serverConn, outputChan, nil)
+	tailer.Start()
+
+	// The producer writes until told to stop rather than a precomputed
+	// frame count, since net.Pipe is synchronous: once b.N messages have
+	// been drained below, further writes would block forever behind the
+	// tailer's own buffering and deadlock the benchmark. It must be joined
+	// before this iteration returns and b.N is mutated for the next one -
+	// reading b.N from inside the goroutine would race with that mutation.
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			if _, err := clientConn.Write(frame); err != nil {
+				return
+			}
+		}
+	}()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		<-outputChan
+	}
+	close(stop)
+	clientConn.Close() // unblocks a write already in flight
+	<-done
+	tailer.Stop()
+}
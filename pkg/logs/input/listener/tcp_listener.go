@@ -0,0 +1,103 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"context"
+	"net"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// Listener accepts plain TCP connections for a LogSource and starts a
+// Tailer for each one.
+type Listener struct {
+	source       *config.LogSource
+	outputChan   chan message.Message
+	read         func(*Tailer) ([]byte, error)
+	consumerGone <-chan struct{}
+	listener     net.Listener
+
+	mu      sync.Mutex
+	tailers []*Tailer
+}
+
+// NewListener returns a new Listener for source. consumerGone, if non-nil,
+// is wired into every Tailer it spawns via Tailer.WatchConsumerGone, so
+// that an outputChan consumer going away unblocks every tailer's forwarder
+// instead of leaking it until Shutdown is called.
+func NewListener(source *config.LogSource, outputChan chan message.Message, read func(*Tailer) ([]byte, error), consumerGone <-chan struct{}) *Listener {
+	return &Listener{
+		source:       source,
+		outputChan:   outputChan,
+		read:         read,
+		consumerGone: consumerGone,
+	}
+}
+
+// Serve accepts connections off ln and starts a Tailer for each one. ln may
+// be freshly bound, or inherited from a parent process via
+// ListenerFromFileDescriptor. Only the listening socket is inherited:
+// connections the parent had already accepted, and their tailers, are not
+// handed over and end with the parent.
+func (l *Listener) Serve(ln net.Listener) {
+	l.listener = ln
+	go l.run()
+}
+
+func (l *Listener) run() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		tailer := NewTailer(l.source, conn, l.outputChan, l.read)
+		if l.consumerGone != nil {
+			tailer.WatchConsumerGone(l.consumerGone)
+		}
+
+		l.mu.Lock()
+		l.pruneClosedTailers()
+		l.tailers = append(l.tailers, tailer)
+		l.mu.Unlock()
+
+		tailer.Start()
+	}
+}
+
+// pruneClosedTailers drops tailers that have fully shut down. Callers must
+// hold l.mu.
+func (l *Listener) pruneClosedTailers() {
+	live := l.tailers[:0]
+	for _, t := range l.tailers {
+		if !t.Closed() {
+			live = append(live, t)
+		}
+	}
+	l.tailers = live
+}
+
+// Shutdown stops accepting new connections and shuts down every tailer it
+// has spawned, each bounded by ctx's deadline.
+func (l *Listener) Shutdown(ctx context.Context) error {
+	if l.listener != nil {
+		l.listener.Close()
+	}
+
+	l.mu.Lock()
+	tailers := l.tailers
+	l.mu.Unlock()
+
+	var firstErr error
+	for _, t := range tailers {
+		if err := t.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
@@ -0,0 +1,178 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"github.com/DataDog/datadog-agent/pkg/util/log"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// TLSListener accepts TLS connections on a single port and demultiplexes
+// them by SNI server name, so one port can serve multiple LogSources - each
+// with its own certificate, decoder and tags - instead of needing one port
+// per tenant. It parallels Listener, but every accepted connection gets
+// its own tls.Config selected from the client's requested server name.
+type TLSListener struct {
+	sourcesByName map[string]*config.LogSource
+	outputChan    chan message.Message
+	read          func(*Tailer) ([]byte, error)
+	consumerGone  <-chan struct{}
+	listener      net.Listener
+
+	mu      sync.Mutex
+	tailers []*Tailer
+	stop    chan struct{}
+}
+
+// NewTLSListener returns a new TLSListener serving the given sources, each
+// of which must set TLSCert/TLSKey on its LogSource config; sources with no
+// TLSCert configured are ignored. consumerGone, if non-nil, is wired into
+// every Tailer it spawns via Tailer.WatchConsumerGone, so that an
+// outputChan consumer going away unblocks every tailer's forwarder instead
+// of leaking it until Stop is called.
+func NewTLSListener(sources []*config.LogSource, outputChan chan message.Message, read func(*Tailer) ([]byte, error), consumerGone <-chan struct{}) *TLSListener {
+	sourcesByName := make(map[string]*config.LogSource)
+	for _, source := range sources {
+		if source.Config.TLSCert == "" {
+			continue
+		}
+		sourcesByName[source.Name] = source
+	}
+	return &TLSListener{
+		sourcesByName: sourcesByName,
+		outputChan:    outputChan,
+		read:          read,
+		consumerGone:  consumerGone,
+		stop:          make(chan struct{}),
+	}
+}
+
+// Start binds addr and begins accepting TLS connections.
+func (l *TLSListener) Start(addr string) error {
+	ln, err := tls.Listen("tcp", addr, &tls.Config{GetConfigForClient: l.configForClient})
+	if err != nil {
+		return fmt.Errorf("starting TLS listener on %s: %w", addr, err)
+	}
+	l.listener = ln
+	go l.run()
+	return nil
+}
+
+// configForClient picks the tls.Config to use based on the SNI server name
+// the client requested, routing the connection to the matching LogSource.
+func (l *TLSListener) configForClient(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+	source, ok := l.sourcesByName[hello.ServerName]
+	if !ok {
+		return nil, fmt.Errorf("no LogSource configured for SNI server name %q", hello.ServerName)
+	}
+	return tlsConfigForSource(source)
+}
+
+// tlsConfigForSource builds the tls.Config for a single LogSource from its
+// TLSCert/TLSKey/TLSCA/ClientAuth/MinVersion fields.
+func tlsConfigForSource(source *config.LogSource) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(source.Config.TLSCert, source.Config.TLSKey)
+	if err != nil {
+		return nil, fmt.Errorf("loading TLS cert/key for source %s: %w", source.Name, err)
+	}
+	cfg := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   source.Config.MinVersion,
+		ClientAuth:   source.Config.ClientAuth,
+	}
+	if source.Config.TLSCA != "" {
+		ca, err := os.ReadFile(source.Config.TLSCA)
+		if err != nil {
+			return nil, fmt.Errorf("reading TLS CA for source %s: %w", source.Name, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no valid certificates found in TLS CA for source %s", source.Name)
+		}
+		cfg.ClientCAs = pool
+	}
+	return cfg, nil
+}
+
+// run accepts connections until the listener is closed.
+func (l *TLSListener) run() {
+	for {
+		conn, err := l.listener.Accept()
+		if err != nil {
+			return
+		}
+		tlsConn, ok := conn.(*tls.Conn)
+		if !ok {
+			conn.Close()
+			continue
+		}
+		go l.handle(tlsConn)
+	}
+}
+
+// handle completes the TLS handshake, resolves the source it negotiated
+// for, and starts a Tailer reading from it.
+func (l *TLSListener) handle(tlsConn *tls.Conn) {
+	if err := tlsConn.Handshake(); err != nil {
+		log.Warnf("TLS handshake failed: %v", err)
+		tlsConn.Close()
+		return
+	}
+	source, ok := l.sourcesByName[tlsConn.ConnectionState().ServerName]
+	if !ok {
+		log.Warnf("No LogSource for SNI server name %q, closing connection", tlsConn.ConnectionState().ServerName)
+		tlsConn.Close()
+		return
+	}
+
+	tailer := NewTLSTailer(source, tlsConn, l.outputChan, l.read)
+	if l.consumerGone != nil {
+		tailer.WatchConsumerGone(l.consumerGone)
+	}
+	l.mu.Lock()
+	l.tailers = append(l.tailers, tailer)
+	l.mu.Unlock()
+	tailer.Start()
+}
+
+// Shutdown stops accepting new connections and shuts down every tailer it
+// has spawned, each bounded by ctx's deadline. It parallels
+// Listener.Shutdown.
+func (l *TLSListener) Shutdown(ctx context.Context) error {
+	if l.listener != nil {
+		l.listener.Close()
+	}
+
+	l.mu.Lock()
+	tailers := l.tailers
+	l.mu.Unlock()
+
+	var firstErr error
+	for _, t := range tailers {
+		if err := t.Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Stop closes the listening socket and stops every tailer it has spawned,
+// waiting indefinitely for each to flush.
+//
+// Deprecated: use Shutdown with a bounded context instead.
+func (l *TLSListener) Stop() {
+	l.Shutdown(context.Background())
+}
@@ -0,0 +1,203 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// generateSelfSignedCert returns PEM-encoded cert and key bytes for a
+// throwaway self-signed certificate, so TLS listener tests have real
+// files to load without shelling out to openssl.
+func generateSelfSignedCert(t *testing.T, commonName string) (certPEM, keyPEM []byte) {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key: %v", err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageKeyEncipherment | x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		IsCA:         true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &priv.PublicKey, priv)
+	if err != nil {
+		t.Fatalf("creating certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(priv)
+	if err != nil {
+		t.Fatalf("marshaling key: %v", err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM
+}
+
+// writeTestCert writes a fresh self-signed cert/key pair under dir and
+// returns their paths.
+func writeTestCert(t *testing.T, dir, name string) (certPath, keyPath string) {
+	t.Helper()
+	cert, key := generateSelfSignedCert(t, name)
+
+	certPath = filepath.Join(dir, name+"-cert.pem")
+	keyPath = filepath.Join(dir, name+"-key.pem")
+	if err := os.WriteFile(certPath, cert, 0o600); err != nil {
+		t.Fatalf("writing cert: %v", err)
+	}
+	if err := os.WriteFile(keyPath, key, 0o600); err != nil {
+		t.Fatalf("writing key: %v", err)
+	}
+	return certPath, keyPath
+}
+
+func TestTLSConfigForSourceAppliesClientAuthWithoutCA(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCert(t, dir, "no-ca")
+
+	source := config.NewLogSource("no-ca", &config.LogsConfig{
+		TLSCert:    certPath,
+		TLSKey:     keyPath,
+		ClientAuth: tls.RequireAnyClientCert,
+	})
+
+	cfg, err := tlsConfigForSource(source)
+	if err != nil {
+		t.Fatalf("tlsConfigForSource: %v", err)
+	}
+	if cfg.ClientAuth != tls.RequireAnyClientCert {
+		t.Fatalf("got ClientAuth %v, want %v even with no TLSCA configured", cfg.ClientAuth, tls.RequireAnyClientCert)
+	}
+}
+
+func TestTLSConfigForSourceAppliesClientAuthWithCA(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCert(t, dir, "with-ca")
+	caPath := filepath.Join(dir, "ca.pem")
+	caCert, _ := generateSelfSignedCert(t, "ca")
+	if err := os.WriteFile(caPath, caCert, 0o600); err != nil {
+		t.Fatalf("writing ca: %v", err)
+	}
+
+	source := config.NewLogSource("with-ca", &config.LogsConfig{
+		TLSCert:    certPath,
+		TLSKey:     keyPath,
+		TLSCA:      caPath,
+		ClientAuth: tls.RequireAndVerifyClientCert,
+	})
+
+	cfg, err := tlsConfigForSource(source)
+	if err != nil {
+		t.Fatalf("tlsConfigForSource: %v", err)
+	}
+	if cfg.ClientAuth != tls.RequireAndVerifyClientCert {
+		t.Fatalf("got ClientAuth %v, want %v", cfg.ClientAuth, tls.RequireAndVerifyClientCert)
+	}
+	if cfg.ClientCAs == nil {
+		t.Fatalf("expected ClientCAs to be populated from TLSCA")
+	}
+}
+
+func TestTLSListenerConfigForClientRoutesBySNI(t *testing.T) {
+	dir := t.TempDir()
+	certA, keyA := writeTestCert(t, dir, "tenant-a")
+	certB, keyB := writeTestCert(t, dir, "tenant-b")
+
+	sourceA := config.NewLogSource("tenant-a", &config.LogsConfig{TLSCert: certA, TLSKey: keyA})
+	sourceB := config.NewLogSource("tenant-b", &config.LogsConfig{TLSCert: certB, TLSKey: keyB})
+
+	l := NewTLSListener([]*config.LogSource{sourceA, sourceB}, nil, nil, nil)
+
+	cfg, err := l.configForClient(&tls.ClientHelloInfo{ServerName: "tenant-a"})
+	if err != nil {
+		t.Fatalf("configForClient(tenant-a): %v", err)
+	}
+	certA2, err := tls.LoadX509KeyPair(certA, keyA)
+	if err != nil {
+		t.Fatalf("reloading reference cert: %v", err)
+	}
+	if string(cfg.Certificates[0].Certificate[0]) != string(certA2.Certificate[0]) {
+		t.Fatalf("configForClient(tenant-a) returned a different certificate than tenant-a's")
+	}
+
+	if _, err := l.configForClient(&tls.ClientHelloInfo{ServerName: "unknown-tenant"}); err == nil {
+		t.Fatalf("expected an error routing an unconfigured SNI server name")
+	}
+}
+
+func TestNewTLSListenerIgnoresSourcesWithoutCert(t *testing.T) {
+	source := config.NewLogSource("plain", &config.LogsConfig{})
+	l := NewTLSListener([]*config.LogSource{source}, nil, nil, nil)
+	if _, ok := l.sourcesByName["plain"]; ok {
+		t.Fatalf("expected a source with no TLSCert to be ignored")
+	}
+}
+
+// TestTLSListenerShutdownRespectsDeadline is a regression test: Shutdown
+// must bound every tailer it spawned by ctx's deadline, the same way
+// Listener.Shutdown does for plain TCP. Stop used to call the unbounded
+// Tailer.Stop, so a stuck tailer with no consumerGone wired could hang it
+// forever.
+func TestTLSListenerShutdownRespectsDeadline(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := writeTestCert(t, dir, "tenant")
+	source := config.NewLogSource("tenant", &config.LogsConfig{TLSCert: certPath, TLSKey: keyPath})
+	source.OverflowPolicy = string(BlockOverflowPolicy)
+	source.BufferSize = 1
+
+	outputChan := make(chan message.Message) // never drained, no consumerGone wired
+	l := NewTLSListener([]*config.LogSource{source}, outputChan, nil, nil)
+	if err := l.Start("127.0.0.1:0"); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	conn, err := tls.Dial("tcp", l.listener.Addr().String(), &tls.Config{InsecureSkipVerify: true, ServerName: "tenant"})
+	if err != nil {
+		t.Fatalf("tls.Dial: %v", err)
+	}
+	defer conn.Close()
+
+	// write more newline-delimited messages than the buffer can hold, so
+	// the tailer's forwarder ends up stuck trying to deliver to outputChan.
+	for i := 0; i < 5; i++ {
+		if _, err := conn.Write([]byte("line\n")); err != nil {
+			t.Fatalf("Write: %v", err)
+		}
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() { done <- l.Shutdown(ctx) }()
+
+	select {
+	case err := <-done:
+		if err != context.DeadlineExceeded {
+			t.Fatalf("got err %v, want context.DeadlineExceeded", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Shutdown did not return within 5s of its 200ms deadline")
+	}
+}
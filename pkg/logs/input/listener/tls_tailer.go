@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package listener
+
+import (
+	"crypto/tls"
+
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/message"
+)
+
+// NewTLSTailer returns a new Tailer reading from an already-negotiated TLS
+// connection. It behaves exactly like NewTailer; the handshake is expected
+// to have completed (and the SNI server name resolved to source) before the
+// connection is handed off here.
+func NewTLSTailer(source *config.LogSource, tlsConn *tls.Conn, outputChan chan message.Message, read func(*Tailer) ([]byte, error)) *Tailer {
+	return NewTailer(source, tlsConn, outputChan, read)
+}
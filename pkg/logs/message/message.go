@@ -0,0 +1,21 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package message defines the decoded log message type passed between the
+// input, processing and sender stages of the logs pipeline.
+package message
+
+// Message is a single decoded log line ready to be processed and sent.
+type Message struct {
+	Content []byte
+	Origin  *Origin
+	Status  string
+}
+
+// New returns a new Message wrapping content. content's backing array
+// becomes owned by the Message; callers must not keep writing to it.
+func New(content []byte, origin *Origin, status string) Message {
+	return Message{Content: content, Origin: origin, Status: status}
+}
@@ -0,0 +1,36 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+package message
+
+import (
+	"github.com/DataDog/datadog-agent/pkg/logs/config"
+	"github.com/DataDog/datadog-agent/pkg/logs/datastreams"
+)
+
+// Origin carries the metadata describing where a Message came from.
+type Origin struct {
+	LogSource *config.LogSource
+
+	pathway    datastreams.Pathway
+	hasPathway bool
+}
+
+// NewOrigin returns a new Origin for source.
+func NewOrigin(source *config.LogSource) *Origin {
+	return &Origin{LogSource: source}
+}
+
+// SetPathway attaches a Data Streams Monitoring pathway to this origin, so
+// downstream processors can continue it on egress.
+func (o *Origin) SetPathway(pathway datastreams.Pathway) {
+	o.pathway = pathway
+	o.hasPathway = true
+}
+
+// Pathway returns the pathway attached to this origin, if any.
+func (o *Origin) Pathway() (pathway datastreams.Pathway, ok bool) {
+	return o.pathway, o.hasPathway
+}
@@ -0,0 +1,19 @@
+// Unless explicitly stated otherwise all files in this repository are licensed
+// under the Apache License Version 2.0.
+// This product includes software developed at Datadog (https://wwt.datadoghq.com/).
+// Copyright 2018 Datadog, Inc.
+
+// Package log is the agent's logging facade.
+package log
+
+import "log"
+
+// Warnf logs a warning-level message.
+func Warnf(format string, params ...interface{}) {
+	log.Printf("WARN "+format, params...)
+}
+
+// Debugf logs a debug-level message.
+func Debugf(format string, params ...interface{}) {
+	log.Printf("DEBUG "+format, params...)
+}